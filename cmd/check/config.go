@@ -0,0 +1,127 @@
+package check
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// defaultConfigPaths lists the YAML files parseConfig merges, in order, when
+// the caller hasn't overridden them via --config/-c or AHA_CONFIG.
+var defaultConfigPaths = []string{"cmd/default.yaml"}
+
+// SetConfigPaths overrides the config files parseConfig merges. Called by
+// the root command after resolving --config/-c and the AHA_CONFIG fallback.
+func SetConfigPaths(paths []string) {
+	defaultConfigPaths = paths
+}
+
+// loadViper merges defaultConfigPaths in order into a single viper.Viper and
+// layers AHA_-prefixed environment variables on top, so e.g.
+// AHA_CHECK_CONNECTIONS_DB1_PASSWORD overrides check.connections.db1.password.
+func loadViper() (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	v.SetEnvPrefix("AHA")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	for i, path := range defaultConfigPaths {
+		path = strings.TrimSpace(path)
+		v.SetConfigFile(path)
+
+		var err error
+		if i == 0 {
+			err = v.ReadInConfig()
+		} else {
+			err = v.MergeInConfig()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config file %s: %v", path, err)
+		}
+	}
+
+	return v, nil
+}
+
+// expandEnv resolves ${ENV_VAR} references in config values so credentials
+// never need to sit in YAML in plain text.
+func expandEnv(s string) string {
+	return os.ExpandEnv(s)
+}
+
+// expandEnvSlice applies expandEnv to every element of ss.
+func expandEnvSlice(ss []string) []string {
+	expanded := make([]string, len(ss))
+	for i, s := range ss {
+		expanded[i] = expandEnv(s)
+	}
+	return expanded
+}
+
+// retryPolicyAt reads a RetryPolicy from prefix, falling back to fallback
+// for any field that isn't set. This lets check.retry act as the default
+// and check.connections.<name>.retry override it per connection.
+func retryPolicyAt(v *viper.Viper, prefix string, fallback RetryPolicy) RetryPolicy {
+	policy := fallback
+	if v.IsSet(prefix + ".max_attempts") {
+		policy.MaxAttempts = v.GetInt(prefix + ".max_attempts")
+	}
+	if v.IsSet(prefix + ".initial_backoff") {
+		policy.InitialBackoff = v.GetDuration(prefix + ".initial_backoff")
+	}
+	if v.IsSet(prefix + ".max_backoff") {
+		policy.MaxBackoff = v.GetDuration(prefix + ".max_backoff")
+	}
+	if v.IsSet(prefix + ".multiplier") {
+		policy.Multiplier = v.GetFloat64(prefix + ".multiplier")
+	}
+	if v.IsSet(prefix + ".jitter") {
+		policy.Jitter = v.GetFloat64(prefix + ".jitter")
+	}
+	return policy
+}
+
+func parseConfig() (map[string]ConnectionConfig, error) {
+	v, err := loadViper()
+	if err != nil {
+		return nil, err
+	}
+
+	defaultPolicy := retryPolicyAt(v, "check.retry", defaultRetryPolicy)
+
+	connections := make(map[string]ConnectionConfig)
+	connKeys := v.GetStringMap("check.connections")
+
+	for name := range connKeys {
+		prefix := fmt.Sprintf("check.connections.%s", name)
+		config := ConnectionConfig{
+			Type:            expandEnv(v.GetString(prefix + ".type")),
+			IsChecked:       v.GetBool(prefix + ".is_checked"),
+			Host:            expandEnv(v.GetString(prefix + ".host")),
+			Port:            v.GetInt(prefix + ".port"),
+			User:            expandEnv(v.GetString(prefix + ".user")),
+			Password:        expandEnv(v.GetString(prefix + ".password")),
+			Database:        expandEnv(v.GetString(prefix + ".database")),
+			URL:             expandEnv(v.GetString(prefix + ".url")),
+			Method:          expandEnv(v.GetString(prefix + ".method")),
+			Timeout:         expandEnv(v.GetString(prefix + ".timeout")),
+			DB:              v.GetInt(prefix + ".db"),
+			ProjectID:       expandEnv(v.GetString(prefix + ".project_id")),
+			CredentialsJSON: expandEnv(v.GetString(prefix + ".credentials_json")),
+			TopicID:         expandEnv(v.GetString(prefix + ".topic_id")),
+			Address:         expandEnv(v.GetString(prefix + ".address")),
+			TLS:             v.GetBool(prefix + ".tls"),
+			ServiceName:     expandEnv(v.GetString(prefix + ".service_name")),
+			Brokers:         expandEnvSlice(v.GetStringSlice(prefix + ".brokers")),
+			Topic:           expandEnv(v.GetString(prefix + ".topic")),
+			SASLMechanism:   expandEnv(v.GetString(prefix + ".sasl_mechanism")),
+			Retry:           retryPolicyAt(v, prefix+".retry", defaultPolicy),
+		}
+		connections[name] = config
+	}
+
+	return connections, nil
+}
@@ -2,20 +2,31 @@ package check
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"fmt"
 	"net/http"
 	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/pubsub"
 	"github.com/go-redis/redis/v8"
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/jedib0t/go-pretty/v6/table"
-	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 )
 
 func New() *cobra.Command {
@@ -25,12 +36,14 @@ func New() *cobra.Command {
 	}
 
 	cmd.AddCommand(newConnection())
+	cmd.AddCommand(newServe())
+	cmd.AddCommand(newTableSummary())
 
 	return cmd
 }
 
 type Connection interface {
-	CheckConnection() error
+	CheckConnection(ctx context.Context) error
 	GetDescription() string
 }
 
@@ -47,12 +60,17 @@ type HTTPConfig struct {
 	Timeout time.Duration
 }
 
-func (c *HTTPConfig) CheckConnection() error {
+func (c *HTTPConfig) CheckConnection(ctx context.Context) error {
 	client := &http.Client{
 		Timeout: c.Timeout,
 	}
 
-	resp, err := client.Get(c.URL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build HTTP request: %v", err)
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("HTTP connection failed: %v", err)
 	}
@@ -77,7 +95,7 @@ type MySQLConfig struct {
 	Database string
 }
 
-func (c *MySQLConfig) CheckConnection() error {
+func (c *MySQLConfig) CheckConnection(ctx context.Context) error {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?timeout=5s",
 		c.User, c.Password, c.Host, c.Port, c.Database)
 
@@ -88,7 +106,7 @@ func (c *MySQLConfig) CheckConnection() error {
 	defer db.Close()
 
 	// Test the connection
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(ctx); err != nil {
 		return fmt.Errorf("MySQL ping failed: %v", err)
 	}
 	return nil
@@ -106,7 +124,7 @@ type RedisConfig struct {
 	DB       int
 }
 
-func (c *RedisConfig) CheckConnection() error {
+func (c *RedisConfig) CheckConnection(ctx context.Context) error {
 	client := redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%d", c.Host, c.Port),
 		Password: c.Password,
@@ -114,7 +132,6 @@ func (c *RedisConfig) CheckConnection() error {
 	})
 	defer client.Close()
 
-	ctx := context.Background()
 	if err := client.Ping(ctx).Err(); err != nil {
 		return fmt.Errorf("Redis ping failed: %v", err)
 	}
@@ -132,9 +149,7 @@ type PubSubConfig struct {
 	TopicID         string
 }
 
-func (c *PubSubConfig) CheckConnection() error {
-	ctx := context.Background()
-
+func (c *PubSubConfig) CheckConnection(ctx context.Context) error {
 	// 创建 Pub/Sub 客户端
 	var client *pubsub.Client
 	var err error
@@ -168,6 +183,129 @@ func (c *PubSubConfig) GetDescription() string {
 	return fmt.Sprintf("pubsub://%s/topics/%s", c.ProjectID, c.TopicID)
 }
 
+// GRPCConfig represents configuration for a gRPC connection
+type GRPCConfig struct {
+	Address     string
+	TLS         bool
+	ServiceName string
+	Timeout     time.Duration
+}
+
+func (c *GRPCConfig) CheckConnection(ctx context.Context) error {
+	var creds credentials.TransportCredentials
+	if c.TLS {
+		creds = credentials.NewTLS(&tls.Config{})
+	} else {
+		creds = insecure.NewCredentials()
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(dialCtx, c.Address, grpc.WithTransportCredentials(creds), grpc.WithBlock())
+	if err != nil {
+		return fmt.Errorf("failed to dial gRPC endpoint: %v", err)
+	}
+	defer conn.Close()
+
+	if c.ServiceName == "" {
+		return grpcCheckReflection(ctx, conn)
+	}
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: c.ServiceName})
+	if err != nil {
+		return fmt.Errorf("gRPC health check failed: %v", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("gRPC service %s is not serving: %s", c.ServiceName, resp.Status)
+	}
+	return nil
+}
+
+// grpcCheckReflection confirms the endpoint is reachable by listing its
+// services via server reflection, used when no ServiceName is configured.
+func grpcCheckReflection(ctx context.Context, conn *grpc.ClientConn) error {
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open reflection stream: %v", err)
+	}
+	defer stream.CloseSend()
+
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		return fmt.Errorf("failed to send reflection request: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		return fmt.Errorf("gRPC reflection failed: %v", err)
+	}
+	return nil
+}
+
+func (c *GRPCConfig) GetDescription() string {
+	return fmt.Sprintf("grpc://%s", c.Address)
+}
+
+// KafkaConfig represents configuration for a Kafka connection
+type KafkaConfig struct {
+	Brokers       []string
+	Topic         string
+	SASLMechanism string
+	Username      string
+	Password      string
+}
+
+func (c *KafkaConfig) CheckConnection(ctx context.Context) error {
+	if len(c.Brokers) == 0 {
+		return fmt.Errorf("no Kafka brokers configured")
+	}
+
+	dialer := &kafka.Dialer{
+		Timeout:   10 * time.Second,
+		DualStack: true,
+	}
+
+	if c.SASLMechanism != "" {
+		mechanism, err := kafkaSASLMechanism(c.SASLMechanism, c.Username, c.Password)
+		if err != nil {
+			return err
+		}
+		dialer.SASLMechanism = mechanism
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", c.Brokers[0])
+	if err != nil {
+		return fmt.Errorf("failed to dial Kafka broker: %v", err)
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions(c.Topic)
+	if err != nil {
+		return fmt.Errorf("failed to fetch metadata for topic %s: %v", c.Topic, err)
+	}
+	if len(partitions) == 0 {
+		return fmt.Errorf("topic %s has no partitions", c.Topic)
+	}
+	return nil
+}
+
+func kafkaSASLMechanism(name, username, password string) (sasl.Mechanism, error) {
+	switch strings.ToUpper(name) {
+	case "PLAIN":
+		return plain.Mechanism{Username: username, Password: password}, nil
+	case "SCRAM-SHA-256":
+		return scram.Mechanism(scram.SHA256, username, password)
+	case "SCRAM-SHA-512":
+		return scram.Mechanism(scram.SHA512, username, password)
+	default:
+		return nil, fmt.Errorf("unsupported SASL mechanism: %s", name)
+	}
+}
+
+func (c *KafkaConfig) GetDescription() string {
+	return fmt.Sprintf("kafka://%s/%s", strings.Join(c.Brokers, ","), c.Topic)
+}
+
 type ConnectionConfig struct {
 	Type            string
 	IsChecked       bool
@@ -183,42 +321,13 @@ type ConnectionConfig struct {
 	ProjectID       string
 	CredentialsJSON string
 	TopicID         string
-}
-
-func parseConfig(configPath string) (map[string]ConnectionConfig, error) {
-	v := viper.New()
-	v.SetConfigFile(configPath)
-	v.SetConfigType("yaml")
-
-	if err := v.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf("failed to read config file: %v", err)
-	}
-
-	connections := make(map[string]ConnectionConfig)
-	connKeys := v.GetStringMap("check.connections")
-
-	for name := range connKeys {
-		prefix := fmt.Sprintf("check.connections.%s", name)
-		config := ConnectionConfig{
-			Type:            v.GetString(prefix + ".type"),
-			IsChecked:       v.GetBool(prefix + ".is_checked"),
-			Host:            v.GetString(prefix + ".host"),
-			Port:            v.GetInt(prefix + ".port"),
-			User:            v.GetString(prefix + ".user"),
-			Password:        v.GetString(prefix + ".password"),
-			Database:        v.GetString(prefix + ".database"),
-			URL:             v.GetString(prefix + ".url"),
-			Method:          v.GetString(prefix + ".method"),
-			Timeout:         v.GetString(prefix + ".timeout"),
-			DB:              v.GetInt(prefix + ".db"),
-			ProjectID:       v.GetString(prefix + ".project_id"),
-			CredentialsJSON: v.GetString(prefix + ".credentials_json"),
-			TopicID:         v.GetString(prefix + ".topic_id"),
-		}
-		connections[name] = config
-	}
-
-	return connections, nil
+	Address         string
+	TLS             bool
+	ServiceName     string
+	Brokers         []string
+	Topic           string
+	SASLMechanism   string
+	Retry           RetryPolicy
 }
 
 func createResource(name string, config ConnectionConfig) (*Resource, error) {
@@ -258,6 +367,25 @@ func createResource(name string, config ConnectionConfig) (*Resource, error) {
 			CredentialsJSON: config.CredentialsJSON,
 			TopicID:         config.TopicID,
 		}
+	case "grpc":
+		timeout, err := time.ParseDuration(config.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout format: %v", err)
+		}
+		resource.Config = &GRPCConfig{
+			Address:     config.Address,
+			TLS:         config.TLS,
+			ServiceName: config.ServiceName,
+			Timeout:     timeout,
+		}
+	case "kafka":
+		resource.Config = &KafkaConfig{
+			Brokers:       config.Brokers,
+			Topic:         config.Topic,
+			SASLMechanism: config.SASLMechanism,
+			Username:      config.User,
+			Password:      config.Password,
+		}
 	default:
 		return nil, fmt.Errorf("unsupported resource type: %s", config.Type)
 	}
@@ -265,69 +393,158 @@ func createResource(name string, config ConnectionConfig) (*Resource, error) {
 	return &resource, nil
 }
 
+// CheckResult captures the outcome of probing a single named connection.
+type CheckResult struct {
+	Name        string
+	Type        string
+	Description string
+	Connected   bool
+	Skipped     bool
+	Attempts    int
+	FirstErr    error
+	Err         error
+	Latency     time.Duration
+}
+
+// Status returns a short machine-readable label for the result, used by
+// the json/yaml output formats and the serve subcommand's GET /checks JSON.
+func (r CheckResult) Status() string {
+	switch {
+	case r.Skipped:
+		return "skipped"
+	case r.Connected:
+		return "connected"
+	case r.Description == "":
+		return "error"
+	default:
+		return "failed"
+	}
+}
+
+// connectionNames returns the configured connection names in a stable,
+// sorted order so results can be matched back up by index.
+func connectionNames(connections map[string]ConnectionConfig) []string {
+	names := make([]string, 0, len(connections))
+	for name := range connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runChecks probes every named connection in connections, dispatching the
+// work onto a worker pool of the given size so a single slow endpoint cannot
+// stall the rest. Each probe is bounded by timeout. Results are returned in
+// the same order as names, regardless of which worker finished first. When
+// failFast is true, the first failure cancels the shared context so checks
+// still in flight or not yet dispatched abort instead of running to completion.
+func runChecks(ctx context.Context, names []string, connections map[string]ConnectionConfig, concurrency int, timeout time.Duration, failFast bool) []CheckResult {
+	results := make([]CheckResult, len(names))
+	jobs := make(chan int)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var failOnce sync.Once
+	markFailure := func() {
+		if failFast {
+			failOnce.Do(cancel)
+		}
+	}
+
+	var wg sync.WaitGroup
+	worker := func() {
+		defer wg.Done()
+		for i := range jobs {
+			name := names[i]
+			connConfig := connections[name]
+
+			resource, err := createResource(name, connConfig)
+			if err != nil {
+				results[i] = CheckResult{Name: name, Type: connConfig.Type, Err: err}
+				markFailure()
+				continue
+			}
+
+			if !resource.IsChecked {
+				results[i] = CheckResult{Name: name, Type: connConfig.Type, Description: resource.Config.GetDescription(), Skipped: true}
+				continue
+			}
+
+			checkCtx, checkCancel := context.WithTimeout(runCtx, timeout)
+			start := time.Now()
+			attempts, firstErr, lastErr := retry(checkCtx, connConfig.Retry, resource.Config.CheckConnection)
+			latency := time.Since(start)
+			checkCancel()
+
+			if lastErr != nil {
+				markFailure()
+			}
+
+			results[i] = CheckResult{
+				Name:        name,
+				Type:        connConfig.Type,
+				Description: resource.Config.GetDescription(),
+				Connected:   lastErr == nil,
+				Attempts:    attempts,
+				FirstErr:    firstErr,
+				Err:         lastErr,
+				Latency:     latency,
+			}
+		}
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go worker()
+	}
+
+	for i := range names {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
 func newConnection() *cobra.Command {
+	var concurrency int
+	var timeout time.Duration
+	var output string
+	var failFast bool
+
 	cmd := &cobra.Command{
 		Use:   "connection",
 		Short: "资源连通check",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// 解析配置文件
-			connections, err := parseConfig("cmd/default.yaml")
+			connections, err := parseConfig()
 			if err != nil {
 				return err
 			}
 
-			// 创建表格
-			t := table.NewWriter()
-			t.SetOutputMirror(os.Stdout)
-			t.AppendHeader(table.Row{"Name", "Type", "Description", "Status"})
-			t.SetStyle(table.StyleLight)
-
-			// 检查每个连接
-			for name, connConfig := range connections {
-				resource, err := createResource(name, connConfig)
-				if err != nil {
-					t.AppendRow(table.Row{
-						name,
-						connConfig.Type,
-						"",
-						text.FgRed.Sprintf("Error: %v", err),
-					})
-					continue
-				}
-
-				// 如果 IsChecked 为 false，跳过检查
-				if !resource.IsChecked {
-					t.AppendRow(table.Row{
-						name,
-						connConfig.Type,
-						resource.Config.GetDescription(),
-						text.FgYellow.Sprint("Skipped"),
-					})
-					continue
-				}
-
-				err = resource.Config.CheckConnection()
-				if err != nil {
-					t.AppendRow(table.Row{
-						name,
-						connConfig.Type,
-						resource.Config.GetDescription(),
-						text.FgRed.Sprintf("Failed: %v", err),
-					})
-				} else {
-					t.AppendRow(table.Row{
-						name,
-						connConfig.Type,
-						resource.Config.GetDescription(),
-						text.FgGreen.Sprint("Connected"),
-					})
-				}
-			}
+			names := connectionNames(connections)
+			results := runChecks(cmd.Context(), names, connections, concurrency, timeout, failFast)
 
-			t.Render()
+			anyFailed, err := renderResults(os.Stdout, "aha-check-connection", output, results)
+			if err != nil {
+				return err
+			}
+			if anyFailed {
+				return fmt.Errorf("one or more connection checks failed")
+			}
 			return nil
 		},
 	}
 
+	cmd.Flags().IntVar(&concurrency, "concurrency", runtime.NumCPU(), "number of connections to check concurrently")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Second, "timeout for each connection check")
+	cmd.Flags().StringVar(&output, "output", "table", "output format: table, json, yaml, junit")
+	cmd.Flags().BoolVar(&failFast, "fail-fast", false, "cancel remaining checks as soon as one fails")
+
 	return cmd
 }
@@ -0,0 +1,129 @@
+package check
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"gopkg.in/yaml.v3"
+)
+
+// outputRecord is the structured representation of a CheckResult emitted by
+// the json/yaml output formats.
+type outputRecord struct {
+	Name        string `json:"name" yaml:"name"`
+	Type        string `json:"type" yaml:"type"`
+	Description string `json:"description" yaml:"description"`
+	Status      string `json:"status" yaml:"status"`
+	Error       string `json:"error,omitempty" yaml:"error,omitempty"`
+	LatencyMs   int64  `json:"latency_ms" yaml:"latency_ms"`
+}
+
+func toOutputRecords(results []CheckResult) []outputRecord {
+	records := make([]outputRecord, 0, len(results))
+	for _, r := range results {
+		rec := outputRecord{
+			Name:        r.Name,
+			Type:        r.Type,
+			Description: r.Description,
+			Status:      r.Status(),
+			LatencyMs:   r.Latency.Milliseconds(),
+		}
+		if r.Err != nil {
+			rec.Error = r.Err.Error()
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+// junitTestsuite is a minimal JUnit XML testsuite, shared by the connection
+// and table-summary commands' --output junit mode.
+type junitTestsuite struct {
+	XMLName  xml.Name        `xml:"testsuite"`
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestcase `xml:"testcase"`
+}
+
+type junitTestcase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+func toJUnitTestsuite(suiteName string, results []CheckResult) junitTestsuite {
+	suite := junitTestsuite{Name: suiteName, Tests: len(results)}
+	for _, r := range results {
+		tc := junitTestcase{Name: r.Name, Classname: r.Type}
+		switch {
+		case r.Skipped:
+			tc.Skipped = &struct{}{}
+			suite.Skipped++
+		case !r.Connected:
+			tc.Failure = &junitFailure{Message: fmt.Sprintf("%v", r.Err)}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	return suite
+}
+
+// renderResults writes results to w in the given format (table, json, yaml
+// or junit) and reports whether any non-skipped check failed.
+func renderResults(w io.Writer, suiteName, format string, results []CheckResult) (anyFailed bool, err error) {
+	for _, r := range results {
+		if !r.Skipped && !r.Connected {
+			anyFailed = true
+		}
+	}
+
+	switch format {
+	case "table":
+		renderTable(w, results)
+	case "json":
+		err = json.NewEncoder(w).Encode(toOutputRecords(results))
+	case "yaml":
+		err = yaml.NewEncoder(w).Encode(toOutputRecords(results))
+	case "junit":
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		err = enc.Encode(toJUnitTestsuite(suiteName, results))
+	default:
+		err = fmt.Errorf("unsupported output format: %s", format)
+	}
+
+	return anyFailed, err
+}
+
+func renderTable(w io.Writer, results []CheckResult) {
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.AppendHeader(table.Row{"Name", "Type", "Description", "Status", "Attempts"})
+	t.SetStyle(table.StyleLight)
+
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			t.AppendRow(table.Row{r.Name, r.Type, r.Description, text.FgYellow.Sprint("Skipped"), r.Attempts})
+		case r.Connected:
+			t.AppendRow(table.Row{r.Name, r.Type, r.Description, text.FgGreen.Sprint("Connected"), r.Attempts})
+		case r.Description == "":
+			t.AppendRow(table.Row{r.Name, r.Type, "", text.FgRed.Sprintf("Error: %v", r.Err), r.Attempts})
+		default:
+			t.AppendRow(table.Row{r.Name, r.Type, r.Description, text.FgRed.Sprintf("Failed: first=%v last=%v", r.FirstErr, r.Err), r.Attempts})
+		}
+	}
+
+	t.Render()
+}
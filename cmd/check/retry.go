@@ -0,0 +1,72 @@
+package check
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how many times and how aggressively a failed
+// CheckConnection call is retried before being reported as down.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	Jitter         float64
+}
+
+// defaultRetryPolicy applies when neither the connection nor the top-level
+// check.retry block in the config specifies one.
+var defaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    1,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.1,
+}
+
+// retry invokes fn up to policy.MaxAttempts times, sleeping
+// min(MaxBackoff, InitialBackoff*Multiplier^attempt) plus uniform jitter in
+// [0, Jitter*backoff) between attempts. It stops early if ctx is cancelled.
+// It returns the number of attempts made and the errors from the first and
+// the last attempt, so intermittent failures can be told apart from
+// hard-down services.
+func retry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) error) (attempts int, firstErr, lastErr error) {
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	for attempts = 1; attempts <= maxAttempts; attempts++ {
+		err := fn(ctx)
+		lastErr = err
+		if attempts == 1 {
+			firstErr = err
+		}
+		if err == nil {
+			return attempts, firstErr, nil
+		}
+		if attempts == maxAttempts {
+			break
+		}
+
+		backoff := time.Duration(float64(policy.InitialBackoff) * math.Pow(policy.Multiplier, float64(attempts-1)))
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+		sleep := backoff
+		if policy.Jitter > 0 {
+			sleep += time.Duration(rand.Float64() * policy.Jitter * float64(backoff))
+		}
+
+		select {
+		case <-ctx.Done():
+			return attempts, firstErr, lastErr
+		case <-time.After(sleep):
+		}
+	}
+
+	return attempts, firstErr, lastErr
+}
@@ -0,0 +1,172 @@
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// checkCache holds the most recently computed CheckResults, refreshed on a
+// timer so HTTP handlers never block on a live probe.
+type checkCache struct {
+	mu      sync.RWMutex
+	results []CheckResult
+}
+
+func (c *checkCache) set(results []CheckResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results = results
+}
+
+func (c *checkCache) get() []CheckResult {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.results
+}
+
+// checkRecord is the JSON representation of a single CheckResult returned by
+// GET /checks.
+type checkRecord struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+	LatencyMs   int64  `json:"latency_ms"`
+	Error       string `json:"error,omitempty"`
+}
+
+func toCheckRecord(r CheckResult) checkRecord {
+	rec := checkRecord{
+		Name:        r.Name,
+		Type:        r.Type,
+		Description: r.Description,
+		Status:      r.Status(),
+		LatencyMs:   r.Latency.Milliseconds(),
+	}
+	if r.Err != nil {
+		rec.Error = r.Err.Error()
+	}
+	return rec
+}
+
+func (c *checkCache) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	results := c.get()
+
+	healthy := true
+	for _, res := range results {
+		if !res.Skipped && !res.Connected {
+			healthy = false
+			break
+		}
+	}
+
+	if !healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, "unhealthy")
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+func (c *checkCache) handleChecks(w http.ResponseWriter, r *http.Request) {
+	results := c.get()
+	records := make([]checkRecord, 0, len(results))
+	for _, res := range results {
+		records = append(records, toCheckRecord(res))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
+
+var metricsBuckets = []float64{0.1, 0.5, 1, 2, 5, 10}
+
+func (c *checkCache) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	results := c.get()
+
+	var b strings.Builder
+	b.WriteString("# HELP aha_check_up Whether the named check is currently connected (1) or not (0).\n")
+	b.WriteString("# TYPE aha_check_up gauge\n")
+	for _, res := range results {
+		up := 0
+		if res.Connected {
+			up = 1
+		}
+		fmt.Fprintf(&b, "aha_check_up{name=%q,type=%q} %d\n", res.Name, res.Type, up)
+	}
+
+	b.WriteString("# HELP aha_check_duration_seconds Duration of the most recent check.\n")
+	b.WriteString("# TYPE aha_check_duration_seconds histogram\n")
+	for _, res := range results {
+		seconds := res.Latency.Seconds()
+		for _, le := range metricsBuckets {
+			count := 0
+			if seconds <= le {
+				count = 1
+			}
+			fmt.Fprintf(&b, "aha_check_duration_seconds_bucket{name=%q,type=%q,le=%q} %d\n", res.Name, res.Type, fmt.Sprintf("%g", le), count)
+		}
+		fmt.Fprintf(&b, "aha_check_duration_seconds_bucket{name=%q,type=%q,le=\"+Inf\"} 1\n", res.Name, res.Type)
+		fmt.Fprintf(&b, "aha_check_duration_seconds_sum{name=%q,type=%q} %g\n", res.Name, res.Type, seconds)
+		fmt.Fprintf(&b, "aha_check_duration_seconds_count{name=%q,type=%q} 1\n", res.Name, res.Type)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write([]byte(b.String()))
+}
+
+func newServe() *cobra.Command {
+	var addr string
+	var interval time.Duration
+	var concurrency int
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "以 HTTP/JSON 健康检查接口的形式持续暴露 check 结果",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			connections, err := parseConfig()
+			if err != nil {
+				return err
+			}
+			names := connectionNames(connections)
+
+			cache := &checkCache{}
+			refresh := func() {
+				cache.set(runChecks(context.Background(), names, connections, concurrency, timeout, false))
+			}
+			refresh()
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			go func() {
+				for range ticker.C {
+					refresh()
+				}
+			}()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/healthz", cache.handleHealthz)
+			mux.HandleFunc("/checks", cache.handleChecks)
+			mux.HandleFunc("/metrics", cache.handleMetrics)
+
+			fmt.Printf("aha check serve listening on %s (interval=%s)\n", addr, interval)
+			return http.ListenAndServe(addr, mux)
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", ":8080", "address to listen on")
+	cmd.Flags().DurationVar(&interval, "interval", 30*time.Second, "interval between check refreshes")
+	cmd.Flags().IntVar(&concurrency, "concurrency", runtime.NumCPU(), "number of connections to check concurrently")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Second, "timeout for each connection check")
+
+	return cmd
+}
@@ -2,116 +2,452 @@ package check
 
 import (
 	"database/sql"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
-	"log"
 	"os"
+	"runtime"
+	"sort"
+	"sync"
 
 	"github.com/jedib0t/go-pretty/v6/table"
+	"gopkg.in/yaml.v3"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
+// tableSummaryRecord is the structured representation of one table's row
+// count and size, emitted by the json/yaml/junit output formats.
+type tableSummaryRecord struct {
+	Connection string `json:"connection" yaml:"connection"`
+	Table      string `json:"table" yaml:"table"`
+	Rows       int64  `json:"rows" yaml:"rows"`
+	SizeBytes  int64  `json:"size_bytes" yaml:"size_bytes"`
+	Estimated  bool   `json:"estimated" yaml:"estimated"`
+}
+
+// tableDiffRecord reports one schema difference found by --diff: either a
+// table missing from one side, or a column-count mismatch between both.
+type tableDiffRecord struct {
+	Table        string `json:"table" yaml:"table"`
+	OnlyIn       string `json:"only_in,omitempty" yaml:"only_in,omitempty"`
+	ColumnsHere  int    `json:"columns_here,omitempty" yaml:"columns_here,omitempty"`
+	ColumnsThere int    `json:"columns_there,omitempty" yaml:"columns_there,omitempty"`
+}
+
 func newTableSummary() *cobra.Command {
+	var output string
+	var exact bool
+	var concurrency int
+	var diffAgainst string
+
 	cmd := &cobra.Command{
 		Use:   "table-summary",
-		Short: "检查配置连接的数据库中对应的数据总量",
+		Short: "检查配置连接的数据库中对应的数据总量，支持估算/精确行数统计与跨连接 schema diff",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			v := viper.New()
-			v.SetConfigFile("cmd/default.yaml")
-			v.SetConfigType("yaml")
-			if err := v.ReadInConfig(); err != nil {
-				return fmt.Errorf("failed to read config file: %v", err)
+			v, err := loadViper()
+			if err != nil {
+				return err
+			}
+
+			connKeys := v.GetStringMap("check.connections")
+			names := make([]string, 0, len(connKeys))
+			for name := range connKeys {
+				names = append(names, name)
 			}
+			sort.Strings(names)
 
-			conns := v.GetStringMap("check.connections")
-			for name := range conns {
+			connects := make(map[string]*Connect)
+			defer func() {
+				for _, c := range connects {
+					c.Close()
+				}
+			}()
+
+			openConnect := func(name string) (*Connect, error) {
+				if c, ok := connects[name]; ok {
+					return c, nil
+				}
 				prefix := fmt.Sprintf("check.connections.%s", name)
-				typeVal := v.GetString(prefix + ".type")
-				if typeVal != "mysql" {
-					continue
+				if v.GetString(prefix+".type") != "mysql" {
+					return nil, fmt.Errorf("connection %s is not a mysql connection", name)
 				}
-				user := v.GetString(prefix + ".user")
-				password := v.GetString(prefix + ".password")
-				host := v.GetString(prefix + ".host")
+				user := expandEnv(v.GetString(prefix + ".user"))
+				password := expandEnv(v.GetString(prefix + ".password"))
+				host := expandEnv(v.GetString(prefix + ".host"))
 				port := v.GetInt(prefix + ".port")
-				database := v.GetString(prefix + ".database")
+				database := expandEnv(v.GetString(prefix + ".database"))
 				dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", user, password, host, port, database)
-				connect := NewConnect(dsn)
-				tables := connect.GetTables()
 
-				// 输出分组标题
-				fmt.Printf("\n连接名: %s\nDSN: %s\n", name, dsn)
+				c, err := NewConnect(dsn, v.GetInt(prefix+".max_open_conns"), v.GetInt(prefix+".max_idle_conns"))
+				if err != nil {
+					return nil, fmt.Errorf("failed to connect to %s: %v", name, err)
+				}
+				connects[name] = c
+				return c, nil
+			}
+
+			if diffAgainst != "" {
+				other, err := openConnect(diffAgainst)
+				if err != nil {
+					return err
+				}
 
-				tw := table.NewWriter()
-				tw.SetOutputMirror(os.Stdout)
-				tw.AppendHeader(table.Row{"表名称", "记录总数"})
+				var diffs []tableDiffRecord
+				for _, name := range names {
+					if name == diffAgainst {
+						continue
+					}
+					prefix := fmt.Sprintf("check.connections.%s", name)
+					if v.GetString(prefix+".type") != "mysql" {
+						continue
+					}
+					c, err := openConnect(name)
+					if err != nil {
+						return err
+					}
 
-				for _, tbl := range tables {
-					count := connect.GetTableCount(tbl)
-					tw.AppendRow(table.Row{tbl, count})
+					tableDiffs, err := diffSchemas(name, c, diffAgainst, other)
+					if err != nil {
+						return err
+					}
+					diffs = append(diffs, tableDiffs...)
+				}
+
+				return renderTableDiffs(output, diffs)
+			}
+
+			var records []tableSummaryRecord
+			anyFailed := false
+
+			for _, name := range names {
+				prefix := fmt.Sprintf("check.connections.%s", name)
+				if v.GetString(prefix+".type") != "mysql" {
+					continue
+				}
+
+				c, err := openConnect(name)
+				if err != nil {
+					return err
+				}
+
+				stats, err := c.GetTableStats(exact, concurrency)
+				if err != nil {
+					return fmt.Errorf("failed to summarize %s: %v", name, err)
+				}
+
+				for _, s := range stats {
+					if s.Rows < 0 {
+						anyFailed = true
+					}
+					records = append(records, tableSummaryRecord{
+						Connection: name,
+						Table:      s.Name,
+						Rows:       s.Rows,
+						SizeBytes:  s.SizeBytes,
+						Estimated:  s.Estimated,
+					})
 				}
-				tw.Render()
+			}
+
+			if err := renderTableSummary(output, records); err != nil {
+				return err
+			}
+			if anyFailed {
+				return fmt.Errorf("one or more table counts failed to resolve")
 			}
 			return nil
 		},
 	}
+
+	cmd.Flags().StringVar(&output, "output", "table", "output format: table, json, yaml, junit")
+	cmd.Flags().BoolVar(&exact, "exact", false, "use SELECT COUNT(*) instead of information_schema estimates")
+	cmd.Flags().IntVar(&concurrency, "concurrency", runtime.NumCPU(), "number of tables to summarize concurrently (only applies to --exact)")
+	cmd.Flags().StringVar(&diffAgainst, "diff", "", "compare every other mysql connection's schema against this connection name")
+
 	return cmd
 }
 
-type Connect struct {
-	DSN string
+func renderTableSummary(output string, records []tableSummaryRecord) error {
+	switch output {
+	case "table":
+		byConnection := make(map[string][]tableSummaryRecord)
+		var order []string
+		for _, r := range records {
+			if _, ok := byConnection[r.Connection]; !ok {
+				order = append(order, r.Connection)
+			}
+			byConnection[r.Connection] = append(byConnection[r.Connection], r)
+		}
+		for _, name := range order {
+			fmt.Printf("\n连接名: %s\n", name)
+			tw := table.NewWriter()
+			tw.SetOutputMirror(os.Stdout)
+			tw.AppendHeader(table.Row{"表名称", "记录总数", "估算大小(字节)", "是否估算"})
+			for _, r := range byConnection[name] {
+				tw.AppendRow(table.Row{r.Table, r.Rows, r.SizeBytes, r.Estimated})
+			}
+			tw.Render()
+		}
+		return nil
+	case "json":
+		return json.NewEncoder(os.Stdout).Encode(records)
+	case "yaml":
+		return yaml.NewEncoder(os.Stdout).Encode(records)
+	case "junit":
+		suite := junitTestsuite{Name: "aha-table-summary", Tests: len(records)}
+		for _, r := range records {
+			tc := junitTestcase{Name: r.Table, Classname: r.Connection}
+			if r.Rows < 0 {
+				tc.Failure = &junitFailure{Message: "failed to read row count"}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		enc := xml.NewEncoder(os.Stdout)
+		enc.Indent("", "  ")
+		return enc.Encode(suite)
+	default:
+		return fmt.Errorf("unsupported output format: %s", output)
+	}
 }
 
-func NewConnect(dsn string) *Connect {
-	return &Connect{
-		DSN: dsn,
+func renderTableDiffs(output string, diffs []tableDiffRecord) error {
+	switch output {
+	case "table":
+		tw := table.NewWriter()
+		tw.SetOutputMirror(os.Stdout)
+		tw.AppendHeader(table.Row{"表名称", "仅存在于", "列数(此侧)", "列数(对侧)"})
+		for _, d := range diffs {
+			tw.AppendRow(table.Row{d.Table, d.OnlyIn, d.ColumnsHere, d.ColumnsThere})
+		}
+		tw.Render()
+		return nil
+	case "json":
+		return json.NewEncoder(os.Stdout).Encode(diffs)
+	case "yaml":
+		return yaml.NewEncoder(os.Stdout).Encode(diffs)
+	case "junit":
+		suite := junitTestsuite{Name: "aha-table-diff", Tests: len(diffs), Failures: len(diffs)}
+		for _, d := range diffs {
+			suite.Cases = append(suite.Cases, junitTestcase{
+				Name:      d.Table,
+				Classname: "diff",
+				Failure:   &junitFailure{Message: fmt.Sprintf("only_in=%s columns_here=%d columns_there=%d", d.OnlyIn, d.ColumnsHere, d.ColumnsThere)},
+			})
+		}
+		enc := xml.NewEncoder(os.Stdout)
+		enc.Indent("", "  ")
+		return enc.Encode(suite)
+	default:
+		return fmt.Errorf("unsupported output format: %s", output)
 	}
 }
 
-func (c *Connect) GetTables() []string {
-	db, err := sql.Open("mysql", c.DSN)
+// Connect wraps a pooled *sql.DB for a single MySQL DSN. The pool is opened
+// once in NewConnect and reused by GetTables/GetTableStats, instead of each
+// call opening its own short-lived connection.
+type Connect struct {
+	DSN string
+	db  *sql.DB
+}
+
+func NewConnect(dsn string, maxOpenConns, maxIdleConns int) (*Connect, error) {
+	db, err := sql.Open("mysql", dsn)
 	if err != nil {
-		log.Printf("failed to connect to MySQL: %v", err)
-		return nil
+		return nil, fmt.Errorf("failed to connect to MySQL: %v", err)
+	}
+	if maxOpenConns > 0 {
+		db.SetMaxOpenConns(maxOpenConns)
 	}
-	defer db.Close()
+	if maxIdleConns > 0 {
+		db.SetMaxIdleConns(maxIdleConns)
+	}
+	return &Connect{DSN: dsn, db: db}, nil
+}
 
-	rows, err := db.Query("SHOW TABLES")
+func (c *Connect) Close() error {
+	return c.db.Close()
+}
+
+// GetTables lists base tables in the connection's database, excluding views
+// so they aren't confused with real (possibly empty) tables by callers like
+// GetTableStats that report row counts.
+func (c *Connect) GetTables() ([]string, error) {
+	rows, err := c.db.Query(`
+		SELECT TABLE_NAME FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'
+	`)
 	if err != nil {
-		log.Printf("failed to query tables: %v", err)
-		return nil
+		return nil, fmt.Errorf("failed to query tables: %v", err)
 	}
 	defer rows.Close()
 
-	tables := []string{}
+	var tables []string
 	for rows.Next() {
-		var table string
-		if err := rows.Scan(&table); err != nil {
-			log.Printf("failed to scan table name: %v", err)
-			continue
+		var tbl string
+		if err := rows.Scan(&tbl); err != nil {
+			return nil, fmt.Errorf("failed to scan table name: %v", err)
 		}
-		tables = append(tables, table)
-	}
-	if err := rows.Err(); err != nil {
-		log.Printf("row iteration error: %v", err)
+		tables = append(tables, tbl)
 	}
-	return tables
+	return tables, rows.Err()
 }
 
+// GetTableCount returns the exact row count for tableName via SELECT COUNT(*).
 func (c *Connect) GetTableCount(tableName string) int {
-	db, err := sql.Open("mysql", c.DSN)
-	if err != nil {
+	var count int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM `%s`", tableName)
+	if err := c.db.QueryRow(query).Scan(&count); err != nil {
 		return -1
 	}
-	defer db.Close()
+	return count
+}
+
+// tableEstimates reads TABLE_ROWS and DATA_LENGTH+INDEX_LENGTH from
+// information_schema.TABLES, which MySQL tracks without scanning the table
+// and is therefore O(1) rather than O(rows).
+func (c *Connect) tableEstimates() (rows map[string]int64, sizeBytes map[string]int64, err error) {
+	res, err := c.db.Query(`
+		SELECT TABLE_NAME, TABLE_ROWS, DATA_LENGTH + INDEX_LENGTH
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'
+	`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read information_schema.TABLES: %v", err)
+	}
+	defer res.Close()
+
+	rows = make(map[string]int64)
+	sizeBytes = make(map[string]int64)
+	for res.Next() {
+		var name string
+		var rowCount, size sql.NullInt64
+		if err := res.Scan(&name, &rowCount, &size); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan information_schema.TABLES row: %v", err)
+		}
+		rows[name] = rowCount.Int64
+		sizeBytes[name] = size.Int64
+	}
+	return rows, sizeBytes, res.Err()
+}
+
+// TableStat is the row-count/size summary for one table, either estimated
+// from information_schema or computed exactly via SELECT COUNT(*).
+type TableStat struct {
+	Name      string
+	Rows      int64
+	SizeBytes int64
+	Estimated bool
+}
+
+// GetTableStats summarizes every table in the connection's database. By
+// default it uses the fast information_schema estimate; with exact set it
+// falls back to SELECT COUNT(*), dispatched across a worker pool of the
+// given size so one large table doesn't stall the rest.
+func (c *Connect) GetTableStats(exact bool, concurrency int) ([]TableStat, error) {
+	names, err := c.GetTables()
+	if err != nil {
+		return nil, err
+	}
+
+	estRows, estSize, err := c.tableEstimates()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]TableStat, len(names))
+	for i, name := range names {
+		stats[i] = TableStat{Name: name, Rows: estRows[name], SizeBytes: estSize[name], Estimated: true}
+	}
+
+	if !exact {
+		return stats, nil
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	worker := func() {
+		defer wg.Done()
+		for i := range jobs {
+			stats[i].Rows = int64(c.GetTableCount(names[i]))
+			stats[i].Estimated = false
+		}
+	}
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go worker()
+	}
+	for i := range names {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return stats, nil
+}
+
+// diffSchemas compares the tables (and their column counts) visible in a and
+// b, labeling any difference with the given connection names so staging vs.
+// production drift is easy to spot.
+func diffSchemas(nameA string, a *Connect, nameB string, b *Connect) ([]tableDiffRecord, error) {
+	tablesA, err := a.GetTables()
+	if err != nil {
+		return nil, err
+	}
+	tablesB, err := b.GetTables()
+	if err != nil {
+		return nil, err
+	}
+
+	setB := make(map[string]bool, len(tablesB))
+	for _, t := range tablesB {
+		setB[t] = true
+	}
+	setA := make(map[string]bool, len(tablesA))
+	for _, t := range tablesA {
+		setA[t] = true
+	}
 
+	var diffs []tableDiffRecord
+	for _, t := range tablesA {
+		if !setB[t] {
+			diffs = append(diffs, tableDiffRecord{Table: t, OnlyIn: nameA})
+			continue
+		}
+		colsA, err := a.columnCount(t)
+		if err != nil {
+			return nil, err
+		}
+		colsB, err := b.columnCount(t)
+		if err != nil {
+			return nil, err
+		}
+		if colsA != colsB {
+			diffs = append(diffs, tableDiffRecord{Table: t, ColumnsHere: colsA, ColumnsThere: colsB})
+		}
+	}
+	for _, t := range tablesB {
+		if !setA[t] {
+			diffs = append(diffs, tableDiffRecord{Table: t, OnlyIn: nameB})
+		}
+	}
+
+	return diffs, nil
+}
+
+func (c *Connect) columnCount(tableName string) (int, error) {
 	var count int
-	query := fmt.Sprintf("SELECT COUNT(*) FROM `%s`", tableName)
-	err = db.QueryRow(query).Scan(&count)
+	err := c.db.QueryRow(`
+		SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+	`, tableName).Scan(&count)
 	if err != nil {
-		return -1
+		return 0, fmt.Errorf("failed to count columns for %s: %v", tableName, err)
 	}
-	return count
+	return count, nil
 }
@@ -2,20 +2,39 @@ package cmd
 
 import (
 	"errors"
+	"os"
+	"strings"
 
 	"github.com/pix-platform/aha/cmd/check"
 	"github.com/spf13/cobra"
 )
 
 func New() *cobra.Command {
+	var configFlag string
+
 	rootCmd := &cobra.Command{
 		Use:   "aha",
 		Short: "aha 是一个小工具",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			paths := configFlag
+			if paths == "" {
+				paths = os.Getenv("AHA_CONFIG")
+			}
+			if paths == "" {
+				return nil
+			}
+
+			check.SetConfigPaths(strings.Split(paths, ","))
+			return nil
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return errors.New("no additional command provided")
 		},
 	}
 
+	rootCmd.PersistentFlags().StringVarP(&configFlag, "config", "c", "",
+		"comma-separated list of config files to merge (default: cmd/default.yaml; falls back to $AHA_CONFIG)")
+
 	rootCmd.AddCommand(
 		check.New(),
 	)